@@ -0,0 +1,192 @@
+package statsd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricLineRe parses a composed StatsD wire line back into its name,
+// value, type, optional sampling rate (from *Sampled calls), and optional
+// DogStatsD tag suffix, so AggregatingSink can group repeated
+// counters/gauges by canonical metric identity before flushing.
+var metricLineRe = regexp.MustCompile(`^([^:]+):(\+?-?[0-9.]+)\|([a-z]+)(?:\|@([0-9.]+))?(?:\|#(.*))?$`)
+
+// AggregatingSink wraps a Sink and collapses repeated counters and gauges
+// received within a flush interval into a single consolidated line each,
+// and deduplicates set values, cutting packet volume for hot metrics the
+// way the DataDog Go client's client-side aggregation does. Timings, gauge
+// deltas, and anything it can't parse as a counter, gauge, or set are
+// forwarded immediately, since they need per-sample fidelity.
+type AggregatingSink struct {
+	next          Sink
+	flushInterval time.Duration
+
+	mu       sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+	sets     map[string]map[string]struct{}
+	stop     chan struct{}
+}
+
+// NewAggregatingSink wraps next, periodically draining aggregated counters,
+// gauges, and sets into it every flushInterval.
+func NewAggregatingSink(next Sink, flushInterval time.Duration) *AggregatingSink {
+	s := &AggregatingSink{
+		next:          next,
+		flushInterval: flushInterval,
+		counters:      make(map[string]int64),
+		gauges:        make(map[string]float64),
+		sets:          make(map[string]map[string]struct{}),
+		stop:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// PushMetric aggregates counters, absolute gauge values, and sets in memory;
+// gauge deltas and everything else are forwarded to the wrapped sink as-is.
+func (s *AggregatingSink) PushMetric(m string) {
+	match := metricLineRe.FindStringSubmatch(m)
+	if match == nil {
+		s.next.PushMetric(m)
+		return
+	}
+	name, rawValue, kind, rate, tags := match[1], match[2], match[3], match[4], match[5]
+
+	switch kind {
+	case "c":
+		value, err := strconv.ParseInt(rawValue, 10, 64)
+		if err != nil {
+			s.next.PushMetric(m)
+			return
+		}
+		// Samples taken at the same rate scale linearly, so summing the
+		// raw counts of same-rate samples and emitting one consolidated
+		// "|@rate" line is equivalent to the server receiving them
+		// individually. Keying in the rate keeps differently-sampled
+		// calls to the same metric from being mixed together.
+		key := canonicalMetricKey(name, tags, rate)
+		s.mu.Lock()
+		s.counters[key] += value
+		s.mu.Unlock()
+	case "g":
+		// A leading '+' or '-' marks a gauge delta (Gauge also uses a
+		// leading '-' to zero-then-set a negative absolute value); either
+		// way the sign means "apply relative to the current value", which
+		// "last value wins" aggregation cannot represent once collapsed.
+		// Forward deltas untouched, the same way timings are.
+		if strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-") {
+			s.next.PushMetric(m)
+			return
+		}
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			s.next.PushMetric(m)
+			return
+		}
+		key := canonicalMetricKey(name, tags, "")
+		s.mu.Lock()
+		s.gauges[key] = value
+		s.mu.Unlock()
+	case "s":
+		key := canonicalMetricKey(name, tags, "")
+		s.mu.Lock()
+		if s.sets[key] == nil {
+			s.sets[key] = make(map[string]struct{})
+		}
+		s.sets[key][rawValue] = struct{}{}
+		s.mu.Unlock()
+	default:
+		// timings/histograms need per-sample fidelity
+		s.next.PushMetric(m)
+	}
+}
+
+// Shutdown flushes any pending aggregates and stops the background ticker.
+func (s *AggregatingSink) Shutdown() {
+	close(s.stop)
+	s.flush()
+	s.next.Shutdown()
+}
+
+func (s *AggregatingSink) run() {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flush drains the counter/gauge/set maps under lock, emitting one
+// consolidated line per key. Counters and sets reset to empty afterward;
+// gauges persist, since a gauge's StatsD semantics is "last known value".
+func (s *AggregatingSink) flush() {
+	s.mu.Lock()
+	counters := s.counters
+	s.counters = make(map[string]int64)
+	sets := s.sets
+	s.sets = make(map[string]map[string]struct{})
+	gauges := make(map[string]float64, len(s.gauges))
+	for k, v := range s.gauges {
+		gauges[k] = v
+	}
+	s.mu.Unlock()
+
+	for key, value := range counters {
+		name, rateSuffix, tagSuffix := splitMetricKey(key)
+		s.next.PushMetric(fmt.Sprintf("%s:%d|c%s%s", name, value, rateSuffix, tagSuffix))
+	}
+	for key, value := range gauges {
+		name, _, tagSuffix := splitMetricKey(key)
+		s.next.PushMetric(fmt.Sprintf("%s:%g|g%s", name, value, tagSuffix))
+	}
+	for key, values := range sets {
+		name, _, tagSuffix := splitMetricKey(key)
+		for v := range values {
+			s.next.PushMetric(fmt.Sprintf("%s:%s|s%s", name, v, tagSuffix))
+		}
+	}
+}
+
+// metricKeySep joins a metric's name, sampling rate, and canonical tag
+// suffix in the in-memory aggregation keys; it can't appear in a composed
+// wire line.
+const metricKeySep = "\x1f"
+
+// canonicalMetricKey identifies a metric by name, sampling rate (empty for
+// unsampled metrics), and tags sorted into a deterministic order, so two
+// calls tagging the same series in a different order still aggregate
+// together, while different rates for the same name/tags stay distinct.
+func canonicalMetricKey(name, tagSuffix, rate string) string {
+	if tagSuffix == "" {
+		return name + metricKeySep + rate + metricKeySep
+	}
+	tags := strings.Split(tagSuffix, ",")
+	sort.Strings(tags)
+	return name + metricKeySep + rate + metricKeySep + strings.Join(tags, ",")
+}
+
+// splitMetricKey reverses canonicalMetricKey, returning the metric name, a
+// ready-to-append "|@rate" suffix (empty if unsampled), and a
+// ready-to-append "|#k:v,..." tag suffix (empty if there were no tags).
+func splitMetricKey(key string) (name string, rateSuffix string, tagSuffix string) {
+	parts := strings.SplitN(key, metricKeySep, 3)
+	name = parts[0]
+	if len(parts) > 1 && parts[1] != "" {
+		rateSuffix = "|@" + parts[1]
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		tagSuffix = "|#" + parts[2]
+	}
+	return name, rateSuffix, tagSuffix
+}
@@ -0,0 +1,138 @@
+package statsd
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mu      sync.Mutex
+	metrics []string
+}
+
+func (f *fakeSink) PushMetric(m string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.metrics = append(f.metrics, m)
+}
+
+func (f *fakeSink) Shutdown() {}
+
+func (f *fakeSink) snapshot() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]string, len(f.metrics))
+	copy(out, f.metrics)
+	return out
+}
+
+func TestAggregatingSinkCollapsesCounters(t *testing.T) {
+	fake := &fakeSink{}
+	sink := NewAggregatingSink(fake, time.Hour)
+	defer sink.Shutdown()
+
+	sink.PushMetric("hits:1|c")
+	sink.PushMetric("hits:1|c")
+	sink.PushMetric("hits:3|c")
+
+	sink.flush()
+
+	got := fake.snapshot()
+	if len(got) != 1 || got[0] != "hits:5|c" {
+		t.Errorf("expected a single consolidated 'hits:5|c', got %v", got)
+	}
+}
+
+func TestAggregatingSinkCollapsesSampledCounters(t *testing.T) {
+	fake := &fakeSink{}
+	sink := NewAggregatingSink(fake, time.Hour)
+	defer sink.Shutdown()
+
+	sink.PushMetric("hits:1|c|@0.5")
+	sink.PushMetric("hits:1|c|@0.5")
+	sink.PushMetric("hits:1|c|@0.1") // different rate, must not mix with the above
+
+	sink.flush()
+
+	got := fake.snapshot()
+	want := map[string]bool{"hits:2|c|@0.5": false, "hits:1|c|@0.1": false}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d consolidated lines, got %v", len(want), got)
+	}
+	for _, line := range got {
+		if _, ok := want[line]; !ok {
+			t.Errorf("unexpected line %q", line)
+		}
+		want[line] = true
+	}
+	for line, seen := range want {
+		if !seen {
+			t.Errorf("missing expected line %q, got %v", line, got)
+		}
+	}
+}
+
+func TestAggregatingSinkKeepsLastGaugeValue(t *testing.T) {
+	fake := &fakeSink{}
+	sink := NewAggregatingSink(fake, time.Hour)
+	defer sink.Shutdown()
+
+	sink.PushMetric("temp:1|g")
+	sink.PushMetric("temp:2|g")
+
+	sink.flush()
+
+	got := fake.snapshot()
+	if len(got) != 1 || got[0] != "temp:2|g" {
+		t.Errorf("expected only the last gauge value 'temp:2|g', got %v", got)
+	}
+}
+
+func TestAggregatingSinkForwardsNegativeGaugePairAtomically(t *testing.T) {
+	fake := &fakeSink{}
+	sink := NewAggregatingSink(fake, time.Hour)
+	defer sink.Shutdown()
+
+	// Gauge/FGauge push the zero-then-negative-set pair as a single
+	// PushMetric call; AggregatingSink must forward it untouched and in
+	// order rather than buffering the "0" half as a last-value gauge.
+	sink.PushMetric("temp:0|g\ntemp:-5|g")
+
+	sink.flush()
+
+	got := fake.snapshot()
+	if len(got) != 1 || got[0] != "temp:0|g\ntemp:-5|g" {
+		t.Errorf("expected the zero/negative pair forwarded intact and in order, got %v", got)
+	}
+}
+
+func TestAggregatingSinkBypassesGaugeDeltas(t *testing.T) {
+	fake := &fakeSink{}
+	sink := NewAggregatingSink(fake, time.Hour)
+	defer sink.Shutdown()
+
+	sink.PushMetric("temp:+5|g")
+	sink.PushMetric("temp:-2|g")
+
+	sink.flush()
+
+	got := fake.snapshot()
+	if len(got) != 2 || got[0] != "temp:+5|g" || got[1] != "temp:-2|g" {
+		t.Errorf("expected gauge deltas to pass through unaggregated, got %v", got)
+	}
+}
+
+func TestAggregatingSinkBypassesTimings(t *testing.T) {
+	fake := &fakeSink{}
+	sink := NewAggregatingSink(fake, time.Hour)
+	defer sink.Shutdown()
+
+	sink.PushMetric("req:12|ms")
+	sink.PushMetric("req:34|ms")
+
+	got := fake.snapshot()
+	if len(got) != 2 {
+		t.Errorf("expected timings to pass through unaggregated, got %v", got)
+	}
+}
@@ -27,24 +27,71 @@ func init() {
 
 // StatsdClient is a client library to send events to StatsD
 type StatsdClient struct {
-	addr   string
-	prefix string
-	Logger Logger
-	sink   *StatsdSink
+	addr         string
+	prefix       string
+	Logger       Logger
+	sink         Sink
+	baseTags     []Tag
+	tagFormat    TagFormat
+	tagSeparator string
 }
 
-// NewStatsdClient - Factory
+// NewStatsdClient - Factory. This is a convenience wrapper around
+// NewStatsdClientWithSink for the common case of talking UDP StatsD; use
+// NewStatsdClientWithSink directly to plug in a UDS or TCP/statsite sink.
 func NewStatsdClient(addr string, prefix string, maxPacketSize int, flushInterval time.Duration) *StatsdClient {
+	return NewStatsdClientWithSink(addr, prefix, NewStatsdSink(addr, maxPacketSize, flushInterval))
+}
+
+// NewStatsdClientWithAggregation is like NewStatsdClient, but collapses
+// repeated counters and gauges received within aggregationInterval into a
+// single consolidated line each before they reach the wire. Timings bypass
+// aggregation since they need per-sample fidelity. This cuts packet volume
+// for hot counters at the cost of that interval's worth of reporting delay.
+func NewStatsdClientWithAggregation(addr string, prefix string, maxPacketSize int, flushInterval time.Duration,
+	aggregationInterval time.Duration) *StatsdClient {
+	sink := NewAggregatingSink(NewStatsdSink(addr, maxPacketSize, flushInterval), aggregationInterval)
+	return NewStatsdClientWithSink(addr, prefix, sink)
+}
+
+// NewStatsdClientWithSink creates a StatsdClient over a caller-supplied
+// Sink, letting callers pick UDP (StatsdSink), UDS (StatsdSink over a
+// UDSTransport), TCP/statsite (StatsiteSink), or any other Sink
+// implementation.
+func NewStatsdClientWithSink(addr string, prefix string, sink Sink) *StatsdClient {
 	// allow %HOST% in the prefix string
 	prefix = strings.Replace(prefix, "%HOST%", Hostname, 1)
-	client := &StatsdClient{
-		addr:   addr,
-		prefix: prefix,
-		Logger: log.New(os.Stdout, "[StatsdClient] ", log.Ldate|log.Ltime),
-		sink:   NewStatsdSink(addr, maxPacketSize, flushInterval),
+	return &StatsdClient{
+		addr:         addr,
+		prefix:       prefix,
+		Logger:       log.New(os.Stdout, "[StatsdClient] ", log.Ldate|log.Ltime),
+		sink:         sink,
+		tagFormat:    DogStatsDTags,
+		tagSeparator: ",",
 	}
+}
 
-	return client
+// WithBaseTags attaches tags that are merged into every metric this client
+// sends from then on, analogous to labels like node_id/datacenter that
+// should be stamped on everything a process emits.
+func (c *StatsdClient) WithBaseTags(tags ...Tag) *StatsdClient {
+	c.baseTags = append(c.baseTags, tags...)
+	return c
+}
+
+// WithTagFormat selects the wire-level tag serialization. DogStatsDTags is
+// the default; use InfluxDBTags to target an InfluxDB/Telegraf StatsD
+// listener instead.
+func (c *StatsdClient) WithTagFormat(format TagFormat) *StatsdClient {
+	c.tagFormat = format
+	return c
+}
+
+// WithTagSeparator overrides the separator used between DogStatsD-style
+// tags (the default is a comma).
+func (c *StatsdClient) WithTagSeparator(sep string) *StatsdClient {
+	c.tagSeparator = sep
+	return c
 }
 
 // String returns the StatsD server address
@@ -67,31 +114,59 @@ func (c *StatsdClient) Close() error {
 // or also https://github.com/b/statsd_spec
 
 // Incr - Increment a counter metric. Often used to note a particular event
-func (c *StatsdClient) Incr(stat string, count int64) error {
+func (c *StatsdClient) Incr(stat string, count int64, tags ...Tag) error {
 	if 0 != count {
-		return c.send(stat, "%d|c", count)
+		return c.send(stat, "%d|c", count, tags...)
+	}
+	return nil
+}
+
+// IncrSampled is like Incr, but only sends the metric with probability rate
+// (0 < rate <= 1), appending "|@rate" so the server scales the count back
+// up. Useful for thinning a very high-QPS counter client-side.
+func (c *StatsdClient) IncrSampled(stat string, count int64, rate float64, tags ...Tag) error {
+	if 0 != count {
+		return c.sendRated(stat, "%d|c", count, rate, tags...)
 	}
 	return nil
 }
 
 // Decr - Decrement a counter metric. Often used to note a particular event
-func (c *StatsdClient) Decr(stat string, count int64) error {
+func (c *StatsdClient) Decr(stat string, count int64, tags ...Tag) error {
+	if 0 != count {
+		return c.send(stat, "%d|c", -count, tags...)
+	}
+	return nil
+}
+
+// DecrSampled is the sampled counterpart of Decr; see IncrSampled.
+func (c *StatsdClient) DecrSampled(stat string, count int64, rate float64, tags ...Tag) error {
 	if 0 != count {
-		return c.send(stat, "%d|c", -count)
+		return c.sendRated(stat, "%d|c", -count, rate, tags...)
 	}
 	return nil
 }
 
 // Timing - Track a duration event
 // the time delta must be given in milliseconds
-func (c *StatsdClient) Timing(stat string, delta int64) error {
-	return c.send(stat, "%d|ms", delta)
+func (c *StatsdClient) Timing(stat string, delta int64, tags ...Tag) error {
+	return c.send(stat, "%d|ms", delta, tags...)
+}
+
+// TimingSampled is the sampled counterpart of Timing; see IncrSampled.
+func (c *StatsdClient) TimingSampled(stat string, delta int64, rate float64, tags ...Tag) error {
+	return c.sendRated(stat, "%d|ms", delta, rate, tags...)
 }
 
 // PrecisionTiming - Track a duration event
 // the time delta has to be a duration
-func (c *StatsdClient) PrecisionTiming(stat string, delta time.Duration) error {
-	return c.send(stat, fmt.Sprintf("%.6f%s|ms", float64(delta)/float64(time.Millisecond), "%d"), 0)
+func (c *StatsdClient) PrecisionTiming(stat string, delta time.Duration, tags ...Tag) error {
+	return c.send(stat, fmt.Sprintf("%.6f%s|ms", float64(delta)/float64(time.Millisecond), "%d"), 0, tags...)
+}
+
+// PrecisionTimingSampled is the sampled counterpart of PrecisionTiming; see IncrSampled.
+func (c *StatsdClient) PrecisionTimingSampled(stat string, delta time.Duration, rate float64, tags ...Tag) error {
+	return c.sendRated(stat, fmt.Sprintf("%.6f%s|ms", float64(delta)/float64(time.Millisecond), "%d"), 0, rate, tags...)
 }
 
 // Gauge - Gauges are a constant data type. They are not subject to averaging,
@@ -100,71 +175,121 @@ func (c *StatsdClient) PrecisionTiming(stat string, delta time.Duration) error {
 // delta to be true, that specifies that the gauge should be updated, not set. Due to the
 // underlying protocol, you can't explicitly set a gauge to a negative number without
 // first setting it to zero.
-func (c *StatsdClient) Gauge(stat string, value int64) error {
+func (c *StatsdClient) Gauge(stat string, value int64, tags ...Tag) error {
 	if value < 0 {
-		c.send(stat, "%d|g", 0)
-		return c.send(stat, "%d|g", value)
+		return c.sendZeroThenNegativeGauge(stat, "%d|g", value, tags...)
 	}
-	return c.send(stat, "%d|g", value)
+	return c.send(stat, "%d|g", value, tags...)
 }
 
 // GaugeDelta -- Send a change for a gauge
-func (c *StatsdClient) GaugeDelta(stat string, value int64) error {
+func (c *StatsdClient) GaugeDelta(stat string, value int64, tags ...Tag) error {
 	// Gauge Deltas are always sent with a leading '+' or '-'. The '-' takes care of itself but the '+' must added by hand
 	if value < 0 {
-		return c.send(stat, "%d|g", value)
+		return c.send(stat, "%d|g", value, tags...)
 	}
-	return c.send(stat, "+%d|g", value)
+	return c.send(stat, "+%d|g", value, tags...)
 }
 
 // FGauge -- Send a floating point value for a gauge
-func (c *StatsdClient) FGauge(stat string, value float64) error {
+func (c *StatsdClient) FGauge(stat string, value float64, tags ...Tag) error {
 	if value < 0 {
-		c.send(stat, "%d|g", 0)
-		return c.send(stat, "%g|g", value)
+		return c.sendZeroThenNegativeGauge(stat, "%g|g", value, tags...)
 	}
-	return c.send(stat, "%g|g", value)
+	return c.send(stat, "%g|g", value, tags...)
 }
 
 // FGaugeDelta -- Send a floating point change for a gauge
-func (c *StatsdClient) FGaugeDelta(stat string, value float64) error {
+func (c *StatsdClient) FGaugeDelta(stat string, value float64, tags ...Tag) error {
 	if value < 0 {
-		return c.send(stat, "%g|g", value)
+		return c.send(stat, "%g|g", value, tags...)
 	}
-	return c.send(stat, "+%g|g", value)
+	return c.send(stat, "+%g|g", value, tags...)
 }
 
 // Absolute - Send absolute-valued metric (not averaged/aggregated)
-func (c *StatsdClient) Absolute(stat string, value int64) error {
-	return c.send(stat, "%d|a", value)
+func (c *StatsdClient) Absolute(stat string, value int64, tags ...Tag) error {
+	return c.send(stat, "%d|a", value, tags...)
 }
 
 // FAbsolute - Send absolute-valued floating point metric (not averaged/aggregated)
-func (c *StatsdClient) FAbsolute(stat string, value float64) error {
-	return c.send(stat, "%g|a", value)
+func (c *StatsdClient) FAbsolute(stat string, value float64, tags ...Tag) error {
+	return c.send(stat, "%g|a", value, tags...)
 }
 
 // Total - Send a metric that is continously increasing, e.g. read operations since boot
-func (c *StatsdClient) Total(stat string, value int64) error {
-	return c.send(stat, "%d|t", value)
+func (c *StatsdClient) Total(stat string, value int64, tags ...Tag) error {
+	return c.send(stat, "%d|t", value, tags...)
 }
 
-// write a UDP packet with the statsd event
-func (c *StatsdClient) send(stat string, format string, value interface{}) error {
+// write a UDP packet with the statsd event, tagged per the client's
+// configured TagFormat and merged with any base tags
+func (c *StatsdClient) send(stat string, format string, value interface{}, tags ...Tag) error {
+	return c.sendRated(stat, format, value, 1, tags...)
+}
+
+// sendZeroThenNegativeGauge composes the zero-then-negative-set pair the
+// StatsD protocol requires to express an absolute negative gauge value
+// (there's no way to set a gauge negative directly), and pushes both lines
+// as a single PushMetric call. Sending them individually would let a sink
+// that buffers or reorders metrics (e.g. AggregatingSink) split the pair
+// apart and apply the zero-reset after the negative value, corrupting the
+// result; pushing them together keeps them atomic from the sink's
+// perspective regardless of what it does internally.
+func (c *StatsdClient) sendZeroThenNegativeGauge(stat string, format string, value interface{}, tags ...Tag) error {
 	stat = strings.Replace(stat, "%HOST%", Hostname, 1)
-	format = fmt.Sprintf("%s%s:%s", c.prefix, stat, format)
-	metricValue := fmt.Sprintf(format, value)
+	merged := c.mergeTags(tags)
+
+	name := stat
+	suffix := ""
+	if c.tagFormat == InfluxDBTags {
+		name += influxDBTagSegment(merged)
+	} else {
+		suffix = c.dogStatsdTagSuffix(merged)
+	}
+
+	zeroLine := fmt.Sprintf("%s%s:%d|g%s", c.prefix, name, 0, suffix)
+	valueLine := fmt.Sprintf("%s%s:%s%s", c.prefix, name, fmt.Sprintf(format, value), suffix)
+	c.sink.PushMetric(zeroLine + "\n" + valueLine)
+	return nil
+}
 
-	c.sink.PushMetric(metricValue)
+// write a UDP packet with the statsd event, tagged per the client's
+// configured TagFormat and merged with any base tags. rate < 1 makes the
+// send probabilistic, appending "|@rate" to the line when it goes out so
+// the server can scale the count back up; the sampling decision is made
+// before any formatting work, so dropped metrics cost almost nothing.
+func (c *StatsdClient) sendRated(stat string, format string, value interface{}, rate float64, tags ...Tag) error {
+	if !shouldSample(rate) {
+		return nil
+	}
+
+	stat = strings.Replace(stat, "%HOST%", Hostname, 1)
+	merged := c.mergeTags(tags)
+
+	suffix := ""
+	if c.tagFormat == InfluxDBTags {
+		stat += influxDBTagSegment(merged)
+	} else {
+		suffix = c.dogStatsdTagSuffix(merged)
+	}
+
+	rateSuffix := ""
+	if rate > 0 && rate < 1 {
+		rateSuffix = fmt.Sprintf("|@%g", rate)
+	}
+
+	metricValue := fmt.Sprintf(format, value)
+	c.sink.PushMetric(fmt.Sprintf("%s%s:%s%s%s", c.prefix, stat, metricValue, rateSuffix, suffix))
 	return nil
 }
 
-// SendEvent - Sends stats from an event object
+// SendEvent - Sends stats from an event object, merging the event's own
+// tags with the client's base tags and honoring the client's TagFormat
 func (c *StatsdClient) SendEvent(e event.Event) error {
+	merged := c.mergeTags(e.Tags())
 	for _, stat := range e.Stats() {
-		//fmt.Printf("SENDING EVENT %s%s\n", c.prefix, stat)
-
-		c.sink.PushMetric(fmt.Sprintf("%s%s", c.prefix, stat))
+		c.sink.PushMetric(c.formatEventLine(stat, merged))
 	}
 	return nil
 }
@@ -9,8 +9,18 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/Unix4ever/statsd/event"
 )
 
+type fakeEvent struct {
+	stats []string
+	tags  []event.Tag
+}
+
+func (e fakeEvent) Stats() []string   { return e.stats }
+func (e fakeEvent) Tags() []event.Tag { return e.tags }
+
 func newLocalListenerUDP(t *testing.T) (*net.UDPConn, *net.UDPAddr) {
 	udpAddr, err := net.ResolveUDPAddr("udp", ":1200")
 	if err != nil {
@@ -88,6 +98,84 @@ func TestTotal(t *testing.T) {
 	}
 }
 
+func TestTagsDogStatsD(t *testing.T) {
+	ln, udpAddr := newLocalListenerUDP(t)
+	defer ln.Close()
+
+	client := NewStatsdClient(udpAddr.String(), "myproject.", 100, time.Second)
+	client.WithBaseTags(Tag{Name: "region", Value: "us-east-1"})
+
+	ch := make(chan string, 0)
+	go doListenUDP(ln, ch, 1)
+
+	client.Incr("hits", 1, Tag{Name: "env", Value: "prod"})
+
+	x := strings.TrimSpace(<-ch)
+	expected := "myproject.hits:1|c|#region:us-east-1,env:prod"
+	if x != expected {
+		t.Errorf("expected '%s', actual '%s'", expected, x)
+	}
+}
+
+func TestTagsInfluxDB(t *testing.T) {
+	ln, udpAddr := newLocalListenerUDP(t)
+	defer ln.Close()
+
+	client := NewStatsdClient(udpAddr.String(), "myproject.", 100, time.Second)
+	client.WithTagFormat(InfluxDBTags)
+
+	ch := make(chan string, 0)
+	go doListenUDP(ln, ch, 1)
+
+	client.Gauge("temp", 42, Tag{Name: "host", Value: "a"})
+
+	x := strings.TrimSpace(<-ch)
+	expected := "myproject.temp,host=a:42|g"
+	if x != expected {
+		t.Errorf("expected '%s', actual '%s'", expected, x)
+	}
+}
+
+func TestNegativeGaugeSentAsOrderedPair(t *testing.T) {
+	ln, udpAddr := newLocalListenerUDP(t)
+	defer ln.Close()
+
+	client := NewStatsdClient(udpAddr.String(), "myproject.", 100, time.Second)
+
+	ch := make(chan string, 0)
+	go doListenUDP(ln, ch, 2)
+
+	client.Gauge("temp", -5)
+
+	first := strings.TrimSpace(<-ch)
+	second := strings.TrimSpace(<-ch)
+	if first != "myproject.temp:0|g" || second != "myproject.temp:-5|g" {
+		t.Errorf("expected the zero-then-negative pair in order, got %q then %q", first, second)
+	}
+}
+
+func TestSendEventInfluxDB(t *testing.T) {
+	ln, udpAddr := newLocalListenerUDP(t)
+	defer ln.Close()
+
+	client := NewStatsdClient(udpAddr.String(), "myproject.", 100, time.Second)
+	client.WithTagFormat(InfluxDBTags)
+
+	ch := make(chan string, 0)
+	go doListenUDP(ln, ch, 1)
+
+	client.SendEvent(fakeEvent{
+		stats: []string{"hits:1|c"},
+		tags:  []event.Tag{{Name: "host", Value: "a"}},
+	})
+
+	x := strings.TrimSpace(<-ch)
+	expected := "myproject.hits,host=a:1|c"
+	if x != expected {
+		t.Errorf("expected '%s', actual '%s'", expected, x)
+	}
+}
+
 func doListenUDP(conn *net.UDPConn, ch chan string, n int) {
 	for n > 0 {
 		buffer := make([]byte, 1400)
@@ -0,0 +1,19 @@
+// Package event provides the Event interface used by StatsdClient.SendEvent
+// to emit a batch of related metrics in one call.
+package event
+
+// Tag is a single key/value label attached to a metric.
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Event represents a batch of pre-formatted "name:value|type" StatsD lines,
+// optionally carrying tags that should be merged with the client's base tags
+// when the event is sent.
+type Event interface {
+	// Stats returns the serialized "name:value|type" lines for this event.
+	Stats() []string
+	// Tags returns event-level tags to merge with the client's base tags.
+	Tags() []Tag
+}
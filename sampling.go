@@ -0,0 +1,31 @@
+package statsd
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// prngPool hands out a *rand.Rand per sampling decision instead of
+// contending on math/rand's global, mutex-guarded source, keeping sampling
+// checks cheap on hot paths.
+var prngPool = sync.Pool{
+	New: func() interface{} {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	},
+}
+
+// shouldSample reports whether a metric sampled at rate should be kept.
+// rate >= 1 always keeps, rate <= 0 always drops.
+func shouldSample(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	r := prngPool.Get().(*rand.Rand)
+	keep := r.Float64() < rate
+	prngPool.Put(r)
+	return keep
+}
@@ -0,0 +1,25 @@
+package statsd
+
+import "testing"
+
+func TestShouldSampleBounds(t *testing.T) {
+	if !shouldSample(1) {
+		t.Error("rate 1 should always keep")
+	}
+	if shouldSample(0) {
+		t.Error("rate 0 should always drop")
+	}
+}
+
+func TestShouldSampleDistribution(t *testing.T) {
+	const trials = 10000
+	kept := 0
+	for i := 0; i < trials; i++ {
+		if shouldSample(0.5) {
+			kept++
+		}
+	}
+	if kept < trials/4 || kept > trials*3/4 {
+		t.Errorf("expected roughly half of %d trials to be kept, got %d", trials, kept)
+	}
+}
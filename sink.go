@@ -3,37 +3,87 @@ package statsd
 import (
 	"bytes"
 	"log"
-	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// StatsdSink provides a MetricSink that can be used
-// with a statsite or statsd metrics server. It uses
-// only UDP packets, while StatsiteSink uses TCP.
+// defaultReconnectInterval is used when a caller doesn't need control over
+// how often the sink proactively redials its transport.
+const defaultReconnectInterval = 30 * time.Second
+
+// Sink is implemented by anything that can receive composed StatsD metric
+// lines and eventually deliver them to a backend. StatsdClient depends on
+// this interface rather than *StatsdSink directly so sinks can be layered,
+// e.g. wrapping a StatsdSink with an AggregatingSink.
+type Sink interface {
+	PushMetric(m string)
+	Shutdown()
+}
+
+// StatsdSink provides a MetricSink that can be used with a statsite or
+// statsd metrics server. Metrics are written over a pluggable Transport,
+// UDP by default; see NewStatsdSinkWithTransport for UDS or other
+// transports.
 type StatsdSink struct {
-	addr              string
+	transport         Transport
 	metricQueue       chan string
 	statsdMaxLen      int
 	flushInterval     time.Duration
 	reconnectInterval time.Duration
+	telemetryStop     chan struct{}
+	telemetryWG       sync.WaitGroup
+
+	// Health counters surfaced through Stats(); see SinkStats for field meanings.
+	metricsEnqueued int64
+	metricsDropped  int64
+	packetsSent     int64
+	packetsFailed   int64
+	bytesSent       int64
+	reconnects      int64
+}
+
+// NewStatsdSink is used to create a new StatsdSink. addr is dialed over UDP
+// unless it carries a "unix://" or "unixgram://" prefix, in which case a
+// UDSTransport is used instead.
+func NewStatsdSink(addr string, statsdMaxLen int, flushInterval time.Duration) *StatsdSink {
+	return NewStatsdSinkWithTransport(newTransport(addr), statsdMaxLen, flushInterval, defaultReconnectInterval)
 }
 
-// NewStatsdSink is used to create a new StatsdSink
-func NewStatsdSink(addr string, statsdMaxLen int, flushInterval time.Duration,
+// NewStatsdSinkWithTransport is used to create a new StatsdSink over a
+// caller-supplied Transport, and with an explicit reconnectInterval for how
+// often the sink proactively redials even absent a write error.
+func NewStatsdSinkWithTransport(transport Transport, statsdMaxLen int, flushInterval time.Duration,
 	reconnectInterval time.Duration) *StatsdSink {
 	s := &StatsdSink{
-		addr:              addr,
+		transport:         transport,
 		metricQueue:       make(chan string, 4096),
 		statsdMaxLen:      statsdMaxLen,
 		flushInterval:     flushInterval,
 		reconnectInterval: reconnectInterval,
+		telemetryStop:     make(chan struct{}),
 	}
 	go s.flushMetrics()
 	return s
 }
 
-// Close is used to stop flushing to statsd
+// WithWriteTimeout bounds how long a flush may block when the transport's
+// underlying socket buffer is full; without it such a write waits until
+// the socket has room. Only takes effect for transports that support it (e.g.
+// UDSTransport); it is a no-op otherwise.
+func (s *StatsdSink) WithWriteTimeout(d time.Duration) *StatsdSink {
+	if tt, ok := s.transport.(TimeoutTransport); ok {
+		tt.SetWriteTimeout(d)
+	}
+	return s
+}
+
+// Shutdown is used to stop flushing to statsd. The self-telemetry reporter, if
+// any, is signaled and joined before metricQueue is closed so it can't push
+// a tick's metrics to a closed channel and panic.
 func (s *StatsdSink) Shutdown() {
+	close(s.telemetryStop)
+	s.telemetryWG.Wait()
 	close(s.metricQueue)
 }
 
@@ -41,13 +91,14 @@ func (s *StatsdSink) Shutdown() {
 func (s *StatsdSink) PushMetric(m string) {
 	select {
 	case s.metricQueue <- m:
+		atomic.AddInt64(&s.metricsEnqueued, 1)
 	default:
+		atomic.AddInt64(&s.metricsDropped, 1)
 	}
 }
 
 // Flushes metrics
 func (s *StatsdSink) flushMetrics() {
-	var sock net.Conn
 	var err error
 	var wait <-chan time.Time
 	ticker := time.NewTicker(s.flushInterval)
@@ -61,7 +112,8 @@ CONNECT:
 
 RECONNECT:
 	// Attempt to connect
-	sock, err = net.Dial("udp", s.addr)
+	atomic.AddInt64(&s.reconnects, 1)
+	err = s.transport.Reconnect()
 	if err != nil {
 		log.Printf("[ERR] Error connecting to statsd! Err: %s", err)
 		goto WAIT
@@ -77,12 +129,16 @@ RECONNECT:
 
 			// Check if this would overflow the packet size
 			if len(metric)+buf.Len() > s.statsdMaxLen {
-				_, err := sock.Write(buf.Bytes())
+				n := buf.Len()
+				err := s.transport.Write(buf.Bytes())
 				buf.Reset()
 				if err != nil {
+					atomic.AddInt64(&s.packetsFailed, 1)
 					log.Printf("[ERR] Error writing to statsd! Err: %s", err)
 					goto WAIT
 				}
+				atomic.AddInt64(&s.packetsSent, 1)
+				atomic.AddInt64(&s.bytesSent, int64(n))
 			}
 
 			if buf.Len() > 0 {
@@ -96,12 +152,16 @@ RECONNECT:
 				continue
 			}
 
-			_, err := sock.Write(buf.Bytes())
+			n := buf.Len()
+			err := s.transport.Write(buf.Bytes())
 			buf.Reset()
 			if err != nil {
+				atomic.AddInt64(&s.packetsFailed, 1)
 				log.Printf("[ERR] Error flushing to statsd! Err: %s", err)
 				goto WAIT
 			}
+			atomic.AddInt64(&s.packetsSent, 1)
+			atomic.AddInt64(&s.bytesSent, int64(n))
 		case <-reconnectTicker.C:
 			log.Printf("Reconnecting to statsd")
 			goto RECONNECT
@@ -0,0 +1,67 @@
+package statsd
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// SinkStats is a point-in-time snapshot of a StatsdSink's own health
+// counters, returned by StatsdSink.Stats.
+type SinkStats struct {
+	// MetricsEnqueued counts metrics successfully queued by PushMetric.
+	MetricsEnqueued int64
+	// MetricsDropped counts metrics PushMetric dropped because the queue was full.
+	MetricsDropped int64
+	// PacketsSent counts flushed buffers successfully written to the transport.
+	PacketsSent int64
+	// PacketsFailed counts flushed buffers the transport failed to write.
+	PacketsFailed int64
+	// BytesSent counts bytes successfully written to the transport.
+	BytesSent int64
+	// Reconnects counts transport (re)connection attempts, including the initial dial.
+	Reconnects int64
+}
+
+// Stats returns a snapshot of the sink's internal health counters.
+func (s *StatsdSink) Stats() SinkStats {
+	return SinkStats{
+		MetricsEnqueued: atomic.LoadInt64(&s.metricsEnqueued),
+		MetricsDropped:  atomic.LoadInt64(&s.metricsDropped),
+		PacketsSent:     atomic.LoadInt64(&s.packetsSent),
+		PacketsFailed:   atomic.LoadInt64(&s.packetsFailed),
+		BytesSent:       atomic.LoadInt64(&s.bytesSent),
+		Reconnects:      atomic.LoadInt64(&s.reconnects),
+	}
+}
+
+// WithSelfTelemetry periodically emits the sink's own SinkStats as gauges
+// under namespace (e.g. "statsd.client"), giving operators visibility into
+// otherwise-silent packet loss such as PushMetric's queue-full drops.
+func (s *StatsdSink) WithSelfTelemetry(namespace string, interval time.Duration) *StatsdSink {
+	s.telemetryWG.Add(1)
+	go s.reportSelfTelemetry(namespace, interval)
+	return s
+}
+
+func (s *StatsdSink) reportSelfTelemetry(namespace string, interval time.Duration) {
+	defer s.telemetryWG.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stats := s.Stats()
+			// Gauges, not counters: the underlying values are cumulative
+			// totals since the sink started, not per-interval deltas.
+			s.PushMetric(fmt.Sprintf("%s.enqueued:%d|g", namespace, stats.MetricsEnqueued))
+			s.PushMetric(fmt.Sprintf("%s.dropped:%d|g", namespace, stats.MetricsDropped))
+			s.PushMetric(fmt.Sprintf("%s.packets_sent:%d|g", namespace, stats.PacketsSent))
+			s.PushMetric(fmt.Sprintf("%s.packets_failed:%d|g", namespace, stats.PacketsFailed))
+			s.PushMetric(fmt.Sprintf("%s.bytes_sent:%d|g", namespace, stats.BytesSent))
+			s.PushMetric(fmt.Sprintf("%s.reconnects:%d|g", namespace, stats.Reconnects))
+		case <-s.telemetryStop:
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package statsd
+
+import (
+	"testing"
+	"time"
+)
+
+type noopTransport struct{}
+
+func (noopTransport) Write(b []byte) error { return nil }
+func (noopTransport) Reconnect() error     { return nil }
+
+func TestSelfTelemetryShutdownDoesNotPanic(t *testing.T) {
+	sink := NewStatsdSinkWithTransport(noopTransport{}, 1400, time.Millisecond, time.Hour)
+	sink.WithSelfTelemetry("statsd.client", time.Microsecond)
+	// Give the reporter goroutine a chance to be mid-tick before Shutdown
+	// races with it; Shutdown must still not panic on a closed metricQueue.
+	time.Sleep(5 * time.Millisecond)
+	sink.Shutdown()
+}
+
+func TestSinkStatsEnqueuedAndDropped(t *testing.T) {
+	s := &StatsdSink{
+		metricQueue:   make(chan string, 2),
+		telemetryStop: make(chan struct{}),
+	}
+
+	s.PushMetric("a:1|c")
+	s.PushMetric("b:1|c")
+	s.PushMetric("c:1|c") // queue is full, should be dropped
+
+	stats := s.Stats()
+	if stats.MetricsEnqueued != 2 {
+		t.Errorf("expected 2 enqueued, got %d", stats.MetricsEnqueued)
+	}
+	if stats.MetricsDropped != 1 {
+		t.Errorf("expected 1 dropped, got %d", stats.MetricsDropped)
+	}
+}
@@ -0,0 +1,118 @@
+package statsd
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"time"
+)
+
+// StatsiteSink is a Sink that streams metrics over TCP to a statsite (or
+// any newline-framed StatsD-compatible) listener. Unlike StatsdSink's
+// best-effort UDP delivery, PushMetric blocks up to PushTimeout when its
+// buffer is full instead of silently dropping, and the sink reconnects
+// with exponential backoff on write failure, buffering metrics across the
+// reconnect window.
+type StatsiteSink struct {
+	addr string
+
+	metricQueue chan string
+	stop        chan struct{}
+
+	// PushTimeout bounds how long PushMetric blocks when the buffer is full
+	// before giving up and dropping the metric. Defaults to one second.
+	PushTimeout time.Duration
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// NewStatsiteSink is used to create a new StatsiteSink. queueSize bounds how
+// many metrics may be buffered across a reconnect window before PushMetric
+// starts blocking.
+func NewStatsiteSink(addr string, queueSize int) *StatsiteSink {
+	s := &StatsiteSink{
+		addr:        addr,
+		metricQueue: make(chan string, queueSize),
+		stop:        make(chan struct{}),
+		PushTimeout: time.Second,
+		minBackoff:  100 * time.Millisecond,
+		maxBackoff:  30 * time.Second,
+	}
+	go s.run()
+	return s
+}
+
+// PushMetric enqueues m, blocking up to PushTimeout if the buffer is full
+// rather than dropping it outright.
+func (s *StatsiteSink) PushMetric(m string) {
+	select {
+	case s.metricQueue <- m:
+	case <-time.After(s.PushTimeout):
+		log.Printf("[ERR] StatsiteSink: dropped metric, queue still full after %s", s.PushTimeout)
+	}
+}
+
+// Shutdown stops the connection loop and closes the metric queue.
+func (s *StatsiteSink) Shutdown() {
+	close(s.stop)
+	close(s.metricQueue)
+}
+
+// run dials addr, streams queued metrics to it, and redials with
+// exponential backoff whenever the connection drops.
+func (s *StatsiteSink) run() {
+	backoff := s.minBackoff
+
+	for {
+		conn, err := net.Dial("tcp", s.addr)
+		if err != nil {
+			log.Printf("[ERR] StatsiteSink: error connecting to %s! Err: %s", s.addr, err)
+			select {
+			case <-time.After(backoff):
+			case <-s.stop:
+				return
+			}
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+			continue
+		}
+		backoff = s.minBackoff
+
+		if !s.drainTo(conn) {
+			return
+		}
+	}
+}
+
+// drainTo writes queued metrics to conn, one line per metric, until the
+// connection fails or the sink is shut down. It returns false once the
+// sink is shut down, so run() doesn't attempt to reconnect.
+func (s *StatsiteSink) drainTo(conn net.Conn) bool {
+	defer conn.Close()
+	w := bufio.NewWriter(conn)
+
+	for {
+		select {
+		case metric, ok := <-s.metricQueue:
+			if !ok {
+				w.Flush()
+				return false
+			}
+			if _, err := w.WriteString(metric + "\n"); err != nil {
+				log.Printf("[ERR] StatsiteSink: write failed, reconnecting. Err: %s", err)
+				return true
+			}
+			if len(s.metricQueue) == 0 {
+				if err := w.Flush(); err != nil {
+					log.Printf("[ERR] StatsiteSink: flush failed, reconnecting. Err: %s", err)
+					return true
+				}
+			}
+		case <-s.stop:
+			w.Flush()
+			return false
+		}
+	}
+}
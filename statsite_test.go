@@ -0,0 +1,43 @@
+package statsd
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsiteSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err == nil {
+			ch <- line
+		}
+	}()
+
+	sink := NewStatsiteSink(ln.Addr().String(), 10)
+	defer sink.Shutdown()
+
+	sink.PushMetric("myproject.hits:1|c")
+
+	select {
+	case line := <-ch:
+		if line != "myproject.hits:1|c\n" {
+			t.Errorf("expected 'myproject.hits:1|c\\n', got %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for metric over TCP")
+	}
+}
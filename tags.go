@@ -0,0 +1,75 @@
+package statsd
+
+import (
+	"strings"
+
+	"github.com/Unix4ever/statsd/event"
+)
+
+// Tag is a single key/value label attached to a metric, e.g. {"region", "us-east-1"}.
+type Tag = event.Tag
+
+// TagFormat selects the wire-level tag serialization used when sending tagged metrics.
+type TagFormat int
+
+const (
+	// DogStatsDTags serializes tags DogStatsD-style: metric:value|type|#k1:v1,k2:v2
+	DogStatsDTags TagFormat = iota
+	// InfluxDBTags serializes tags InfluxDB-style: metric,k1=v1,k2=v2:value|type
+	InfluxDBTags
+)
+
+// mergeTags combines the client's base tags with call-site tags, base tags first.
+func (c *StatsdClient) mergeTags(tags []Tag) []Tag {
+	if len(c.baseTags) == 0 {
+		return tags
+	}
+	if len(tags) == 0 {
+		return c.baseTags
+	}
+	merged := make([]Tag, 0, len(c.baseTags)+len(tags))
+	merged = append(merged, c.baseTags...)
+	merged = append(merged, tags...)
+	return merged
+}
+
+// dogStatsdTagSuffix renders tags as a trailing "|#k:v,k:v" block.
+func (c *StatsdClient) dogStatsdTagSuffix(tags []Tag) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	suffix := "|#"
+	for i, t := range tags {
+		if i > 0 {
+			suffix += c.tagSeparator
+		}
+		suffix += t.Name + ":" + t.Value
+	}
+	return suffix
+}
+
+// influxDBTagSegment renders tags as a ",k=v,k=v" segment inserted into the metric name.
+func influxDBTagSegment(tags []Tag) string {
+	segment := ""
+	for _, t := range tags {
+		segment += "," + t.Name + "=" + t.Value
+	}
+	return segment
+}
+
+// formatEventLine applies this client's prefix and configured TagFormat to
+// a single pre-formatted "name:value|type" event line, the same way
+// sendRated does for directly-emitted metrics.
+func (c *StatsdClient) formatEventLine(line string, tags []Tag) string {
+	line = c.prefix + line
+	if len(tags) == 0 {
+		return line
+	}
+	if c.tagFormat == InfluxDBTags {
+		if idx := strings.Index(line, ":"); idx >= 0 {
+			return line[:idx] + influxDBTagSegment(tags) + line[idx:]
+		}
+		return line
+	}
+	return line + c.dogStatsdTagSuffix(tags)
+}
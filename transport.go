@@ -0,0 +1,137 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	udsStreamPrefix = "unix://"
+	udsDgramPrefix  = "unixgram://"
+)
+
+// Transport abstracts the underlying connection a StatsdSink flushes
+// buffered metrics over, so UDP, Unix domain sockets, or other transports
+// can be plugged in without changing the flush loop.
+type Transport interface {
+	// Write sends one flushed buffer of newline-separated metrics.
+	Write(b []byte) error
+	// Reconnect tears down and re-establishes the underlying connection.
+	Reconnect() error
+}
+
+// TimeoutTransport is implemented by transports that can bound how long a
+// Write() may block when the underlying socket buffer is full.
+type TimeoutTransport interface {
+	Transport
+	SetWriteTimeout(d time.Duration)
+}
+
+// newTransport picks a Transport based on an addr prefix: "unix://" for a
+// stream Unix socket, "unixgram://" for a datagram one (e.g.
+// "unixgram:///var/run/dogstatsd.sock"), UDP otherwise.
+func newTransport(addr string) Transport {
+	switch {
+	case strings.HasPrefix(addr, udsDgramPrefix), strings.HasPrefix(addr, udsStreamPrefix):
+		return NewUDSTransport(addr)
+	default:
+		return NewUDPTransport(addr)
+	}
+}
+
+// UDPTransport sends metrics over UDP. This is StatsdSink's original,
+// default behavior.
+type UDPTransport struct {
+	addr string
+	conn net.Conn
+}
+
+// NewUDPTransport is used to create a new UDPTransport
+func NewUDPTransport(addr string) *UDPTransport {
+	return &UDPTransport{addr: addr}
+}
+
+// Write sends b over the UDP connection, dialing it lazily on first use.
+func (t *UDPTransport) Write(b []byte) error {
+	if t.conn == nil {
+		if err := t.Reconnect(); err != nil {
+			return err
+		}
+	}
+	_, err := t.conn.Write(b)
+	return err
+}
+
+// Reconnect redials the UDP connection.
+func (t *UDPTransport) Reconnect() error {
+	if t.conn != nil {
+		t.conn.Close()
+	}
+	conn, err := net.Dial("udp", t.addr)
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	return nil
+}
+
+// UDSTransport sends metrics over a Unix domain socket. This avoids the
+// packet-loss and MTU limits UDP hits in containerized workloads, matching
+// the transport option the DataDog client added for the same reason.
+type UDSTransport struct {
+	network      string // "unix" (stream) or "unixgram" (datagram)
+	addr         string
+	conn         net.Conn
+	writeTimeout time.Duration
+}
+
+// NewUDSTransport is used to create a new UDSTransport. addr is expected to
+// carry a "unix://" or "unixgram://" prefix, which is stripped before
+// dialing; addr is dialed as "unixgram" if no prefix is present.
+func NewUDSTransport(addr string) *UDSTransport {
+	network := "unixgram"
+	switch {
+	case strings.HasPrefix(addr, udsDgramPrefix):
+		addr = strings.TrimPrefix(addr, udsDgramPrefix)
+	case strings.HasPrefix(addr, udsStreamPrefix):
+		network = "unix"
+		addr = strings.TrimPrefix(addr, udsStreamPrefix)
+	}
+	return &UDSTransport{network: network, addr: addr}
+}
+
+// SetWriteTimeout bounds how long Write may block when the socket's buffer
+// is full; once the timeout passes, Write returns an error and the buffer
+// is dropped. Zero (the default) sets no deadline, so Write waits until
+// the socket accepts the data.
+func (t *UDSTransport) SetWriteTimeout(d time.Duration) {
+	t.writeTimeout = d
+}
+
+// Write sends b over the Unix domain socket, dialing it lazily on first use.
+func (t *UDSTransport) Write(b []byte) error {
+	if t.conn == nil {
+		if err := t.Reconnect(); err != nil {
+			return err
+		}
+	}
+	if t.writeTimeout > 0 {
+		t.conn.SetWriteDeadline(time.Now().Add(t.writeTimeout))
+	}
+	_, err := t.conn.Write(b)
+	return err
+}
+
+// Reconnect redials the Unix domain socket.
+func (t *UDSTransport) Reconnect() error {
+	if t.conn != nil {
+		t.conn.Close()
+	}
+	conn, err := net.Dial(t.network, t.addr)
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	return nil
+}